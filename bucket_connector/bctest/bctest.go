@@ -0,0 +1,82 @@
+// Package bctest wires BucketConnector on top of a recorded HTTP transport
+// via cloud.google.com/go/httpreplay, so downstream services can write
+// hermetic integration tests without live GCS access. RecordingModule
+// captures real traffic to replayFile; ReplayModule serves it back.
+package bctest
+
+import (
+	"context"
+	"net/http"
+
+	"cloud.google.com/go/httpreplay"
+	"go.uber.org/fx"
+
+	"github.com/weedbox/gcp-modules/bucket_connector"
+)
+
+// FakeGCSEndpoint is the fixed local address the fixtures in testdata/ were
+// recorded against (see testdata/gen/generate_fixtures.go). Replaying them
+// requires configuring `<scope>.endpoint` to this same value, so the GCS
+// client reconstructs the exact request URLs httpreplay matches against —
+// even though the replayed client never actually dials out to it.
+const FakeGCSEndpoint = "http://127.0.0.1:47623"
+
+// RecordingModule behaves like bucket_connector.Module(scope), except every
+// HTTP request made by the GCS provider is captured to replayFile for later
+// playback via ReplayModule.
+func RecordingModule(scope string, replayFile string) fx.Option {
+
+	return fx.Options(
+		fx.Provide(func(lc fx.Lifecycle) (*http.Client, error) {
+
+			rec, err := httpreplay.NewRecorder(replayFile, nil)
+			if err != nil {
+				return nil, err
+			}
+
+			client, err := rec.Client(context.Background())
+			if err != nil {
+				return nil, err
+			}
+
+			lc.Append(fx.Hook{
+				OnStop: func(ctx context.Context) error {
+					return rec.Close()
+				},
+			})
+
+			return client, nil
+		}),
+		bucket_connector.Module(scope),
+	)
+}
+
+// ReplayModule behaves like bucket_connector.Module(scope), except every
+// HTTP request made by the GCS provider is served from replayFile instead
+// of reaching GCS.
+func ReplayModule(scope string, replayFile string) fx.Option {
+
+	return fx.Options(
+		fx.Provide(func(lc fx.Lifecycle) (*http.Client, error) {
+
+			replayer, err := httpreplay.NewReplayer(replayFile)
+			if err != nil {
+				return nil, err
+			}
+
+			client, err := replayer.Client(context.Background())
+			if err != nil {
+				return nil, err
+			}
+
+			lc.Append(fx.Hook{
+				OnStop: func(ctx context.Context) error {
+					return replayer.Close()
+				},
+			})
+
+			return client, nil
+		}),
+		bucket_connector.Module(scope),
+	)
+}
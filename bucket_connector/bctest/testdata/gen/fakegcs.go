@@ -0,0 +1,200 @@
+//go:build ignore
+
+// fakegcs is a minimal stand-in for the GCS JSON API, implementing just
+// enough of the upload/list/get/delete/download wire protocol for
+// cloud.google.com/go/storage to round-trip against it. It exists solely to
+// drive httpreplay.Recorder when generating the fixtures in
+// bucket_connector/bctest/testdata/, so those fixtures don't require a live
+// GCS bucket. See generate_fixtures.go.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/weedbox/gcp-modules/bucket_connector/bctest"
+)
+
+type fakeObject struct {
+	data        []byte
+	contentType string
+}
+
+// fakeGCS is an in-memory object store exposed over HTTP, covering the
+// handful of GCS JSON API requests the bucket_connector GCS provider makes:
+// multipart upload, prefix listing, attrs, delete, and media download.
+type fakeGCS struct {
+	mu      sync.Mutex
+	objects map[string]*fakeObject
+
+	srv *httptest.Server
+}
+
+func newFakeGCS() *fakeGCS {
+
+	f := &fakeGCS{objects: map[string]*fakeObject{}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/upload/storage/v1/b/{bucket}/o", f.handleUpload)
+	mux.HandleFunc("/b/{bucket}/o", f.handleList)
+	mux.HandleFunc("/b/{bucket}/o/{object...}", f.handleObject)
+	mux.HandleFunc("/{bucket}/{object...}", f.handleMedia)
+
+	// Fixtures are recorded against the fixed address bctest.FakeGCSEndpoint
+	// so the tests replaying them can reconstruct byte-identical request
+	// URLs without knowing an ephemeral port chosen at recording time.
+	addr := strings.TrimPrefix(bctest.FakeGCSEndpoint, "http://")
+	lis, err := net.Listen("tcp", addr)
+	if err != nil {
+		panic(fmt.Errorf("listen on %s (fakeGCS fixtures pin this address, see bctest.FakeGCSEndpoint): %w", addr, err))
+	}
+
+	f.srv = &httptest.Server{Listener: lis, Config: &http.Server{Handler: mux}}
+	f.srv.Start()
+
+	return f
+}
+
+func (f *fakeGCS) URL() string {
+	return f.srv.URL
+}
+
+func (f *fakeGCS) Close() {
+	f.srv.Close()
+}
+
+func (f *fakeGCS) handleUpload(w http.ResponseWriter, r *http.Request) {
+
+	_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	mr := multipart.NewReader(r.Body, params["boundary"])
+
+	metaPart, err := mr.NextPart()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var meta struct {
+		Name        string `json:"name"`
+		ContentType string `json:"contentType"`
+	}
+	if err := json.NewDecoder(metaPart).Decode(&meta); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	dataPart, err := mr.NextPart()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	data, err := io.ReadAll(dataPart)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	f.mu.Lock()
+	f.objects[meta.Name] = &fakeObject{data: data, contentType: meta.ContentType}
+	f.mu.Unlock()
+
+	writeJSON(w, objectResource(r.PathValue("bucket"), meta.Name, data, meta.ContentType))
+}
+
+func (f *fakeGCS) handleList(w http.ResponseWriter, r *http.Request) {
+
+	prefix := r.URL.Query().Get("prefix")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var items []map[string]any
+	for name, obj := range f.objects {
+		if prefix != "" && !hasPrefix(name, prefix) {
+			continue
+		}
+		items = append(items, objectResource(r.PathValue("bucket"), name, obj.data, obj.contentType))
+	}
+
+	writeJSON(w, map[string]any{"kind": "storage#objects", "items": items})
+}
+
+func (f *fakeGCS) handleObject(w http.ResponseWriter, r *http.Request) {
+
+	name := r.PathValue("object")
+
+	f.mu.Lock()
+	obj, ok := f.objects[name]
+	if ok && r.Method == http.MethodDelete {
+		delete(f.objects, name)
+	}
+	f.mu.Unlock()
+
+	if !ok {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeJSON(w, objectResource(r.PathValue("bucket"), name, obj.data, obj.contentType))
+	case http.MethodDelete:
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "unsupported method", http.StatusMethodNotAllowed)
+	}
+}
+
+func (f *fakeGCS) handleMedia(w http.ResponseWriter, r *http.Request) {
+
+	name := r.PathValue("object")
+
+	f.mu.Lock()
+	obj, ok := f.objects[name]
+	f.mu.Unlock()
+
+	if !ok {
+		http.Error(w, "not found", http.StatusNotFound)
+		return
+	}
+
+	if obj.contentType != "" {
+		w.Header().Set("Content-Type", obj.contentType)
+	}
+	w.Write(obj.data)
+}
+
+func objectResource(bucket, name string, data []byte, contentType string) map[string]any {
+	return map[string]any{
+		"kind":        "storage#object",
+		"bucket":      bucket,
+		"name":        name,
+		"size":        fmt.Sprint(len(data)),
+		"contentType": contentType,
+		"md5Hash":     "ZmFrZS1tZDU=",
+		"crc32c":      "ZmFrZS1jcmM=",
+		"generation":  "1",
+		"updated":     "2026-01-01T00:00:00Z",
+	}
+}
+
+func hasPrefix(name, prefix string) bool {
+	return len(name) >= len(prefix) && name[:len(prefix)] == prefix
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
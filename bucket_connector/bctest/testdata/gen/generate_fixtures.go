@@ -0,0 +1,135 @@
+//go:build ignore
+
+// generate_fixtures drives bctest.RecordingModule against the fakeGCS server
+// in this package to produce the .replay fixtures checked into
+// bucket_connector/bctest/testdata/, without needing a live GCS bucket. Run
+// it from the module root with:
+//
+//	go run ./bucket_connector/bctest/testdata/gen/fakegcs.go ./bucket_connector/bctest/testdata/gen/generate_fixtures.go
+//
+// and commit whatever changes under testdata/.
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"cloud.google.com/go/httpreplay"
+	"github.com/spf13/viper"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+	"google.golang.org/api/option"
+
+	"github.com/weedbox/gcp-modules/bucket_connector"
+	"github.com/weedbox/gcp-modules/bucket_connector/bctest"
+	"github.com/weedbox/gcp-modules/bucket_connector/storage"
+)
+
+func main() {
+
+	testdata, err := filepath.Abs(filepath.Join("bucket_connector", "bctest", "testdata"))
+	if err != nil {
+		panic(err)
+	}
+
+	record("bctest_save_file", filepath.Join(testdata, "save_file.replay"), func(conn *bucket_connector.BucketConnector) error {
+		_, err := conn.SaveFile(&bucket_connector.UploaderReq{
+			FileName: "hello.txt",
+			Category: "greetings",
+			RawData:  "aGVsbG8gd29ybGQ=",
+		})
+		return err
+	})
+
+	record("bctest_delete_with_prefix", filepath.Join(testdata, "delete_with_prefix.replay"), func(conn *bucket_connector.BucketConnector) error {
+		if _, err := conn.WriteAsFile("greetings/foo.txt", []byte("hello world"), false); err != nil {
+			return err
+		}
+		return conn.DeleteFileWithPrefix("greetings/")
+	})
+
+	record("bctest_read_and_list", filepath.Join(testdata, "read_and_list.replay"), func(conn *bucket_connector.BucketConnector) error {
+		if _, err := conn.WriteAsFile("greetings/foo.txt", []byte("hello world"), false); err != nil {
+			return err
+		}
+
+		ctx := context.Background()
+		page, err := conn.ListObjects(ctx, "greetings/", storage.ListOptions{})
+		if err != nil {
+			return err
+		}
+		if len(page.Objects) == 0 {
+			return fmt.Errorf("fake GCS returned no objects under greetings/")
+		}
+
+		r, err := conn.NewReader(ctx, page.Objects[0].Name)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+
+		_, err = r.Read(make([]byte, 1))
+		return err
+	})
+}
+
+// record spins up a fresh fakeGCS instance, records everything the GCS
+// provider sends it to replayFile, runs fn against the resulting
+// BucketConnector, and stops the app so the recording flushes to disk.
+//
+// This bypasses bctest.RecordingModule: that helper's recorder authenticates
+// against real GCS via Application Default Credentials, which the fake
+// server has no use for and this checkout has no access to.
+func record(scope, replayFile string, fn func(conn *bucket_connector.BucketConnector) error) {
+
+	gcs := newFakeGCS()
+	defer gcs.Close()
+
+	if err := os.Remove(replayFile); err != nil && !os.IsNotExist(err) {
+		panic(err)
+	}
+
+	rec, err := httpreplay.NewRecorder(replayFile, nil)
+	if err != nil {
+		panic(fmt.Errorf("%s: httpreplay.NewRecorder: %w", scope, err))
+	}
+
+	client, err := rec.Client(context.Background(), option.WithoutAuthentication())
+	if err != nil {
+		panic(fmt.Errorf("%s: rec.Client: %w", scope, err))
+	}
+
+	viper.Set(scope+".bucket_name", "bctest-bucket")
+	viper.Set(scope+".json_key", "")
+	viper.Set(scope+".endpoint", bctest.FakeGCSEndpoint)
+
+	var conn *bucket_connector.BucketConnector
+	app := fx.New(
+		fx.Supply(zap.NewNop(), client),
+		bucket_connector.Module(scope),
+		fx.Populate(&conn),
+	)
+
+	ctx := context.Background()
+	if err := app.Start(ctx); err != nil {
+		panic(fmt.Errorf("%s: app.Start: %w", scope, err))
+	}
+
+	runErr := fn(conn)
+
+	if err := app.Stop(ctx); err != nil {
+		panic(fmt.Errorf("%s: app.Stop: %w", scope, err))
+	}
+
+	if err := rec.Close(); err != nil {
+		panic(fmt.Errorf("%s: rec.Close: %w", scope, err))
+	}
+
+	if runErr != nil {
+		panic(fmt.Errorf("%s: %w", scope, runErr))
+	}
+
+	fmt.Println("recorded", replayFile)
+}
@@ -8,35 +8,37 @@ import (
 	"io"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/spf13/viper"
 	"go.uber.org/fx"
 	"go.uber.org/zap"
 
-	"cloud.google.com/go/storage"
-	"google.golang.org/api/iterator"
-	"google.golang.org/api/option"
-)
-
-var logger *zap.Logger
-
-const (
-	DefaultBucketName = "example.com"
-	DefaultJsonKey    = "gcp.json"
+	"github.com/weedbox/gcp-modules/bucket_connector/providers/azure"
+	"github.com/weedbox/gcp-modules/bucket_connector/providers/b2"
+	"github.com/weedbox/gcp-modules/bucket_connector/providers/gcs"
+	"github.com/weedbox/gcp-modules/bucket_connector/providers/s3"
+	"github.com/weedbox/gcp-modules/bucket_connector/storage"
 )
 
 type UploaderReq struct {
 	FileName string `json:"file_name"`
 	Category string `json:"category"`
 	RawData  string `json:"rowData"`
+	// Private, when true, skips the public-ACL upload and instead stores the
+	// object so it's only reachable via a signed URL from SignedURL.
+	Private bool `json:"private"`
 }
 
+// BucketConnector is a vendor-agnostic facade over a storage.Service. The
+// concrete backend (GCS, S3, Azure Blob, or B2) is selected via the
+// `<scope>.provider` config key and wired in by Module.
 type BucketConnector struct {
-	params Params
-	logger *zap.Logger
-	client *storage.Client
-	scope  string
+	params  Params
+	logger  *zap.Logger
+	storage storage.Service
+	scope   string
 }
 
 type Params struct {
@@ -44,167 +46,143 @@ type Params struct {
 
 	Lifecycle fx.Lifecycle
 	Logger    *zap.Logger
+	Storage   storage.Service
 }
 
+// Module wires BucketConnector for scope, selecting the storage provider
+// named by `<scope>.provider` (one of "gcs", "s3", "azure", "b2"; defaults
+// to "gcs" when unset).
 func Module(scope string) fx.Option {
 
 	var m *BucketConnector
 
 	return fx.Module(
 		scope,
+		providerModule(scope),
 		fx.Provide(func(p Params) *BucketConnector {
 
-			logger = p.Logger.Named(scope)
-
-			m := &BucketConnector{
-				params: p,
-				logger: logger,
-				scope:  scope,
+			m = &BucketConnector{
+				params:  p,
+				logger:  p.Logger.Named(scope),
+				storage: p.Storage,
+				scope:   scope,
 			}
 
-			m.initDefaultConfigs()
-
 			return m
 		}),
 		fx.Populate(&m),
-		fx.Invoke(func(p Params) *BucketConnector {
+	)
+}
 
-			p.Lifecycle.Append(
-				fx.Hook{
-					OnStart: m.onStart,
-					OnStop:  m.onStop,
-				},
-			)
+func providerModule(scope string) fx.Option {
 
-			return m
-		}),
-	)
+	provider := storage.Provider(viper.GetString(fmt.Sprintf("%s.provider", scope)))
+	if provider == "" {
+		provider = storage.DefaultProvider
+	}
+
+	switch provider {
+	case storage.ProviderS3:
+		return s3.Module(scope)
+	case storage.ProviderAzure:
+		return azure.Module(scope)
+	case storage.ProviderB2:
+		return b2.Module(scope)
+	default:
+		return gcs.Module(scope)
+	}
 }
 
 func (c *BucketConnector) getConfigPath(key string) string {
 	return fmt.Sprintf("%s.%s", c.scope, key)
 }
 
-func (c *BucketConnector) initDefaultConfigs() {
-	viper.SetDefault(c.getConfigPath("bucket_name"), DefaultBucketName)
-	viper.SetDefault(c.getConfigPath("json_key"), DefaultJsonKey)
+// Storage exposes the underlying storage.Service for callers that need the
+// full provider-agnostic API (listing, signed URLs, streaming downloads).
+func (c *BucketConnector) Storage() storage.Service {
+	return c.storage
 }
 
-func (c *BucketConnector) onStart(ctx context.Context) error {
+// NewReader opens a streaming reader for the object at path.
+func (c *BucketConnector) NewReader(ctx context.Context, path string) (io.ReadCloser, error) {
+	return c.storage.DownloadObject(ctx, path)
+}
 
-	jsonKey := viper.GetString(c.getConfigPath("json_key"))
+// NewRangeReader opens a streaming reader for length bytes of the object at
+// path, starting at offset. A negative length reads to the end of the
+// object.
+func (c *BucketConnector) NewRangeReader(ctx context.Context, path string, offset, length int64) (io.ReadCloser, error) {
+	return c.storage.DownloadObjectRange(ctx, path, offset, length)
+}
 
-	logger.Info("Starting BucketConnector",
-		zap.String("bucket_name", viper.GetString(c.getConfigPath("bucket_name"))),
-		zap.String("json_key", jsonKey),
-	)
+// ReadAll reads the entire object at path into memory.
+func (c *BucketConnector) ReadAll(ctx context.Context, path string) ([]byte, error) {
 
-	client, err := storage.NewClient(ctx, option.WithCredentialsFile(jsonKey))
+	r, err := c.storage.DownloadObject(ctx, path)
 	if err != nil {
-		c.logger.Error("storage.NewClient Error")
-		return err
+		return nil, err
 	}
+	defer r.Close()
 
-	c.client = client
-
-	return nil
+	return io.ReadAll(r)
 }
 
-func (c *BucketConnector) onStop(ctx context.Context) error {
-
-	c.logger.Info("Stopped BucketConnector")
-
-	return c.client.Close()
+// Stat returns metadata for the object at path without downloading it.
+func (c *BucketConnector) Stat(ctx context.Context, path string) (*storage.ObjectInfo, error) {
+	return c.storage.StatObject(ctx, path)
 }
 
-func (c *BucketConnector) GetBucket() *storage.BucketHandle {
-	bucketName := viper.GetString(c.getConfigPath("bucket_name"))
-	return c.client.Bucket(bucketName)
+// ListObjects returns the objects beneath prefix, one page at a time; pass
+// opts.PageToken from a previous ListPage to fetch the next page.
+func (c *BucketConnector) ListObjects(ctx context.Context, prefix string, opts storage.ListOptions) (*storage.ListPage, error) {
+	return c.storage.ListObjects(ctx, prefix, opts)
 }
 
-func (c *BucketConnector) DeleteFileWithPrefix(filePath string) error {
+// WalkObjects calls fn for every object beneath prefix, transparently
+// following pagination. It stops and returns fn's error as soon as fn
+// returns one.
+func (c *BucketConnector) WalkObjects(ctx context.Context, prefix string, opts storage.ListOptions, fn func(storage.ObjectInfo) error) error {
 
-	bucket := c.GetBucket()
-
-	// Delete the objects with the prefix
-	it := bucket.Objects(context.Background(), &storage.Query{
-		Prefix: filePath,
-	})
 	for {
-		objAttrs, err := it.Next()
-		if err == iterator.Done {
-			break
-		}
+		page, err := c.storage.ListObjects(ctx, prefix, opts)
 		if err != nil {
 			return err
 		}
 
-		err = bucket.Object(objAttrs.Name).Delete(context.Background())
-		if err != nil && err != storage.ErrObjectNotExist {
-			return err
+		for _, obj := range page.Objects {
+			if err := fn(obj); err != nil {
+				return err
+			}
 		}
-	}
 
-	return nil
-}
-
-func (c *BucketConnector) DeleteFile(filePath string) error {
-
-	bucket := c.GetBucket()
-
-	// Delete the object
-	err := bucket.Object(filePath).Delete(context.Background())
-	if err != nil {
-
-		if err == storage.ErrObjectNotExist {
+		if page.NextPageToken == "" {
 			return nil
 		}
-
-		return err
+		opts.PageToken = page.NextPageToken
 	}
-
-	return nil
 }
 
-func (c *BucketConnector) WriteAsFile(filePath string, content []byte) (string, error) {
-
-	bucket := c.GetBucket()
-	w := bucket.Object(filePath).NewWriter(context.Background())
-	w.ACL = []storage.ACLRule{
-		{
-			Entity: storage.AllUsers,
-			Role:   storage.RoleReader,
-		},
-	}
+func (c *BucketConnector) DeleteFileWithPrefix(filePath string) error {
 
-	// Write the content to the bucket
-	reader := bytes.NewReader(content)
-	if _, err := io.Copy(w, reader); err != nil {
-		return "", err
-	}
-	if err := w.Close(); err != nil {
-		return "", err
-	}
+	ctx := context.Background()
 
-	// Preparing external URL
-	u, err := url.Parse(fmt.Sprintf("%v/%v", w.Attrs().Bucket, w.Attrs().Name))
-	if err != nil {
-		return "", err
-	}
+	return c.WalkObjects(ctx, filePath, storage.ListOptions{}, func(obj storage.ObjectInfo) error {
+		return c.storage.DeleteObject(ctx, obj.Name)
+	})
+}
 
-	url := fmt.Sprintf("https://%s", u.EscapedPath())
+func (c *BucketConnector) DeleteFile(filePath string) error {
+	return c.storage.DeleteObject(context.Background(), filePath)
+}
 
-	return url, nil
+func (c *BucketConnector) WriteAsFile(filePath string, content []byte, private bool) (string, error) {
+	return c.upload(filePath, bytes.NewReader(content), private)
 }
 
 func (c *BucketConnector) SaveFile(req *UploaderReq) (string, error) {
-	// new a bucket client
-	ctx := context.Background()
 
-	// decode, err := base64.StdEncoding.DecodeString(req.Data)
 	reader := base64.NewDecoder(base64.StdEncoding, strings.NewReader(req.RawData))
 
-	// init uploder
 	fileName := uuid.New().String()
 	if req.FileName != "" {
 		fileName = req.FileName
@@ -212,31 +190,45 @@ func (c *BucketConnector) SaveFile(req *UploaderReq) (string, error) {
 
 	filePath := fmt.Sprintf("%s/%s", req.Category, fileName)
 
-	bucket := c.client.Bucket(viper.GetString(c.getConfigPath("bucket_name")))
-	w := bucket.Object(filePath).NewWriter(ctx)
-	w.ACL = []storage.ACLRule{{Entity: storage.AllUsers, Role: storage.RoleReader}}
+	return c.upload(filePath, reader, req.Private)
+}
 
-	// upload to bucket
-	if _, err := io.Copy(w, reader); err != nil {
-		c.logger.Error("io.Copy Error")
-		return "", err
-	}
-	if err := w.Close(); err != nil {
-		c.logger.Error("io.Close Error")
+// SignedURL returns a time-limited URL for downloading the (typically
+// private) object at path.
+func (c *BucketConnector) SignedURL(ctx context.Context, path string, expires time.Time) (string, error) {
+	return c.storage.SignURL(ctx, path, storage.SignOptions{
+		Method:  "GET",
+		Expires: expires,
+	})
+}
+
+// SignedUploadURL returns a time-limited URL that a client can PUT contentType
+// to, to upload directly to path without the caller holding credentials.
+func (c *BucketConnector) SignedUploadURL(ctx context.Context, path string, contentType string, expires time.Time) (string, error) {
+	return c.storage.SignURL(ctx, path, storage.SignOptions{
+		Method:      "PUT",
+		Expires:     expires,
+		ContentType: contentType,
+	})
+}
+
+func (c *BucketConnector) upload(filePath string, reader io.Reader, private bool) (string, error) {
+
+	attrs, err := c.storage.UploadObject(context.Background(), filePath, reader, storage.UploadOptions{
+		Private: private,
+	})
+	if err != nil {
+		c.logger.Error("UploadObject Error")
 		return "", err
 	}
 
-	u, err := url.Parse(fmt.Sprintf("%v/%v", w.Attrs().Bucket, w.Attrs().Name))
+	bucketName := viper.GetString(c.getConfigPath("bucket_name"))
+
+	u, err := url.Parse(fmt.Sprintf("%v/%v", bucketName, attrs.Name))
 	if err != nil {
 		c.logger.Error("url.Parse Error")
 		return "", err
 	}
 
-	url := fmt.Sprintf("https://%s", u.EscapedPath())
-
-	return url, nil
-}
-
-func (c *BucketConnector) GetClient() *storage.Client {
-	return c.client
+	return fmt.Sprintf("https://%s", u.EscapedPath()), nil
 }
@@ -0,0 +1,321 @@
+// Package azure is the Azure Blob Storage implementation of
+// bucket_connector/storage.Service.
+package azure
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/container"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/sas"
+	"github.com/spf13/viper"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+
+	bstorage "github.com/weedbox/gcp-modules/bucket_connector/storage"
+)
+
+type Params struct {
+	fx.In
+
+	Lifecycle fx.Lifecycle
+	Logger    *zap.Logger
+}
+
+// Service is the Azure Blob-backed storage.Service.
+type Service struct {
+	params Params
+	logger *zap.Logger
+	client *azblob.Client
+	scope  string
+}
+
+// Module registers the Azure Blob provider under scope and supplies a
+// storage.Service for downstream consumers.
+func Module(scope string) fx.Option {
+
+	var s *Service
+
+	return fx.Module(
+		scope,
+		fx.Provide(func(p Params) *Service {
+
+			s = &Service{
+				params: p,
+				logger: p.Logger.Named(scope),
+				scope:  scope,
+			}
+
+			s.initDefaultConfigs()
+
+			return s
+		}),
+		fx.Provide(func(svc *Service) bstorage.Service { return svc }),
+		fx.Populate(&s),
+		fx.Invoke(func(p Params) {
+			p.Lifecycle.Append(
+				fx.Hook{
+					OnStart: s.onStart,
+					OnStop:  s.onStop,
+				},
+			)
+		}),
+	)
+}
+
+func (s *Service) getConfigPath(key string) string {
+	return fmt.Sprintf("%s.%s", s.scope, key)
+}
+
+func (s *Service) initDefaultConfigs() {
+	viper.SetDefault(s.getConfigPath("bucket_name"), "example.com")
+}
+
+func (s *Service) onStart(ctx context.Context) error {
+
+	account := viper.GetString(s.getConfigPath("account_name"))
+	containerName := viper.GetString(s.getConfigPath("bucket_name"))
+
+	s.logger.Info("Starting Azure Blob provider",
+		zap.String("account_name", account),
+		zap.String("container", containerName),
+	)
+
+	accountKey := viper.GetString(s.getConfigPath("account_key"))
+	cred, err := azblob.NewSharedKeyCredential(account, accountKey)
+	if err != nil {
+		s.logger.Error("azblob.NewSharedKeyCredential Error")
+		return err
+	}
+
+	serviceURL := fmt.Sprintf("https://%s.blob.core.windows.net/", account)
+	client, err := azblob.NewClientWithSharedKeyCredential(serviceURL, cred, nil)
+	if err != nil {
+		s.logger.Error("azblob.NewClientWithSharedKeyCredential Error")
+		return err
+	}
+
+	s.client = client
+
+	return nil
+}
+
+func (s *Service) onStop(ctx context.Context) error {
+	s.logger.Info("Stopped Azure Blob provider")
+	return nil
+}
+
+func (s *Service) containerName() string {
+	return viper.GetString(s.getConfigPath("bucket_name"))
+}
+
+func (s *Service) UploadObject(ctx context.Context, path string, r io.Reader, opts bstorage.UploadOptions) (*bstorage.ObjectInfo, error) {
+
+	uploadOpts := &azblob.UploadStreamOptions{}
+	if opts.ContentType != "" {
+		uploadOpts.HTTPHeaders = &blob.HTTPHeaders{BlobContentType: &opts.ContentType}
+	}
+	if opts.CacheControl != "" {
+		if uploadOpts.HTTPHeaders == nil {
+			uploadOpts.HTTPHeaders = &blob.HTTPHeaders{}
+		}
+		uploadOpts.HTTPHeaders.BlobCacheControl = &opts.CacheControl
+	}
+	if opts.Metadata != nil {
+		uploadOpts.Metadata = toAzureMetadata(opts.Metadata)
+	}
+	if opts.ChunkSize > 0 {
+		uploadOpts.BlockSize = int64(opts.ChunkSize)
+	}
+
+	if _, err := s.client.UploadStream(ctx, s.containerName(), path, r, uploadOpts); err != nil {
+		return nil, err
+	}
+
+	return s.StatObject(ctx, path)
+}
+
+func toAzureMetadata(m map[string]string) map[string]*string {
+	out := make(map[string]*string, len(m))
+	for k, v := range m {
+		v := v
+		out[k] = &v
+	}
+	return out
+}
+
+func (s *Service) DownloadObject(ctx context.Context, path string) (io.ReadCloser, error) {
+
+	resp, err := s.client.DownloadStream(ctx, s.containerName(), path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
+
+func (s *Service) DownloadObjectRange(ctx context.Context, path string, offset, length int64) (io.ReadCloser, error) {
+
+	count := length
+	if count < 0 {
+		count = 0
+	}
+
+	resp, err := s.client.DownloadStream(ctx, s.containerName(), path, &azblob.DownloadStreamOptions{
+		Range: azblob.HTTPRange{Offset: offset, Count: count},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Body, nil
+}
+
+func (s *Service) DeleteObject(ctx context.Context, path string) error {
+
+	_, err := s.client.DeleteBlob(ctx, s.containerName(), path, nil)
+	if err != nil && !bloberror.HasCode(err, bloberror.BlobNotFound) {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Service) ListObjects(ctx context.Context, prefix string, opts bstorage.ListOptions) (*bstorage.ListPage, error) {
+
+	page := &bstorage.ListPage{}
+
+	if opts.Delimiter != "" {
+		containerClient := s.client.ServiceClient().NewContainerClient(s.containerName())
+		pager := containerClient.NewListBlobsHierarchyPager(opts.Delimiter, &container.ListBlobsHierarchyOptions{
+			Prefix:     &prefix,
+			Marker:     optionalString(opts.PageToken),
+			MaxResults: optionalInt32(opts.MaxResults),
+		})
+
+		resp, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, item := range resp.Segment.BlobPrefixes {
+			page.Prefixes = append(page.Prefixes, *item.Name)
+		}
+		for _, item := range resp.Segment.BlobItems {
+			page.Objects = append(page.Objects, blobItemToObjectInfo(item))
+		}
+		if resp.NextMarker != nil {
+			page.NextPageToken = *resp.NextMarker
+		}
+
+		return page, nil
+	}
+
+	pager := s.client.NewListBlobsFlatPager(s.containerName(), &azblob.ListBlobsFlatOptions{
+		Prefix:     &prefix,
+		Marker:     optionalString(opts.PageToken),
+		MaxResults: optionalInt32(opts.MaxResults),
+	})
+
+	resp, err := pager.NextPage(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range resp.Segment.BlobItems {
+		page.Objects = append(page.Objects, blobItemToObjectInfo(item))
+	}
+	if resp.NextMarker != nil {
+		page.NextPageToken = *resp.NextMarker
+	}
+
+	return page, nil
+}
+
+func blobItemToObjectInfo(item *container.BlobItem) bstorage.ObjectInfo {
+
+	info := bstorage.ObjectInfo{Name: *item.Name}
+	if item.Properties != nil {
+		if item.Properties.ContentLength != nil {
+			info.Size = *item.Properties.ContentLength
+		}
+		if item.Properties.LastModified != nil {
+			info.Updated = *item.Properties.LastModified
+		}
+		if item.Properties.ContentType != nil {
+			info.ContentType = *item.Properties.ContentType
+		}
+		if len(item.Properties.ContentMD5) > 0 {
+			info.MD5 = hex.EncodeToString(item.Properties.ContentMD5)
+		}
+	}
+
+	return info
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func optionalInt32(n int) *int32 {
+	if n <= 0 {
+		return nil
+	}
+	v := int32(n)
+	return &v
+}
+
+func (s *Service) StatObject(ctx context.Context, path string) (*bstorage.ObjectInfo, error) {
+
+	props, err := s.client.ServiceClient().
+		NewContainerClient(s.containerName()).
+		NewBlobClient(path).
+		GetProperties(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &bstorage.ObjectInfo{Name: path}
+	if props.ContentLength != nil {
+		info.Size = *props.ContentLength
+	}
+	if props.LastModified != nil {
+		info.Updated = *props.LastModified
+	}
+	if props.ContentType != nil {
+		info.ContentType = *props.ContentType
+	}
+	if len(props.ContentMD5) > 0 {
+		info.MD5 = hex.EncodeToString(props.ContentMD5)
+	}
+
+	return info, nil
+}
+
+func (s *Service) SignURL(ctx context.Context, path string, opts bstorage.SignOptions) (string, error) {
+
+	perms := sas.BlobPermissions{Read: true}
+	if opts.Method == "PUT" {
+		perms = sas.BlobPermissions{Write: true, Create: true}
+	}
+
+	expires := opts.Expires
+	if expires.IsZero() {
+		expires = time.Now().Add(time.Hour)
+	}
+
+	blobClient := s.client.ServiceClient().
+		NewContainerClient(s.containerName()).
+		NewBlobClient(path)
+
+	return blobClient.GetSASURL(perms, expires, nil)
+}
@@ -0,0 +1,235 @@
+// Package b2 is the Backblaze B2 implementation of
+// bucket_connector/storage.Service.
+package b2
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/kurin/blazer/b2"
+	"github.com/spf13/viper"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+
+	bstorage "github.com/weedbox/gcp-modules/bucket_connector/storage"
+)
+
+type Params struct {
+	fx.In
+
+	Lifecycle fx.Lifecycle
+	Logger    *zap.Logger
+}
+
+// Service is the Backblaze B2-backed storage.Service.
+type Service struct {
+	params Params
+	logger *zap.Logger
+	client *b2.Client
+	bucket *b2.Bucket
+	scope  string
+}
+
+// Module registers the B2 provider under scope and supplies a
+// storage.Service for downstream consumers.
+func Module(scope string) fx.Option {
+
+	var s *Service
+
+	return fx.Module(
+		scope,
+		fx.Provide(func(p Params) *Service {
+
+			s = &Service{
+				params: p,
+				logger: p.Logger.Named(scope),
+				scope:  scope,
+			}
+
+			s.initDefaultConfigs()
+
+			return s
+		}),
+		fx.Provide(func(svc *Service) bstorage.Service { return svc }),
+		fx.Populate(&s),
+		fx.Invoke(func(p Params) {
+			p.Lifecycle.Append(
+				fx.Hook{
+					OnStart: s.onStart,
+					OnStop:  s.onStop,
+				},
+			)
+		}),
+	)
+}
+
+func (s *Service) getConfigPath(key string) string {
+	return fmt.Sprintf("%s.%s", s.scope, key)
+}
+
+func (s *Service) initDefaultConfigs() {
+	viper.SetDefault(s.getConfigPath("bucket_name"), "example.com")
+}
+
+func (s *Service) onStart(ctx context.Context) error {
+
+	bucketName := viper.GetString(s.getConfigPath("bucket_name"))
+	keyID := viper.GetString(s.getConfigPath("key_id"))
+	appKey := viper.GetString(s.getConfigPath("application_key"))
+
+	s.logger.Info("Starting B2 provider",
+		zap.String("bucket_name", bucketName),
+	)
+
+	client, err := b2.NewClient(ctx, keyID, appKey)
+	if err != nil {
+		s.logger.Error("b2.NewClient Error")
+		return err
+	}
+
+	bucket, err := client.Bucket(ctx, bucketName)
+	if err != nil {
+		s.logger.Error("b2.Bucket Error")
+		return err
+	}
+
+	s.client = client
+	s.bucket = bucket
+
+	return nil
+}
+
+func (s *Service) onStop(ctx context.Context) error {
+	s.logger.Info("Stopped B2 provider")
+	return nil
+}
+
+func (s *Service) UploadObject(ctx context.Context, path string, r io.Reader, opts bstorage.UploadOptions) (*bstorage.ObjectInfo, error) {
+
+	obj := s.bucket.Object(path)
+
+	var writerOpts []b2.WriterOption
+	if opts.ContentType != "" || opts.Metadata != nil {
+		writerOpts = append(writerOpts, b2.WithAttrsOption(&b2.Attrs{
+			ContentType: opts.ContentType,
+			Info:        opts.Metadata,
+		}))
+	}
+
+	w := obj.NewWriter(ctx, writerOpts...)
+	if opts.ChunkSize > 0 {
+		w.ChunkSize = opts.ChunkSize
+	}
+
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return s.StatObject(ctx, path)
+}
+
+func (s *Service) DownloadObject(ctx context.Context, path string) (io.ReadCloser, error) {
+	return s.bucket.Object(path).NewReader(ctx), nil
+}
+
+func (s *Service) DownloadObjectRange(ctx context.Context, path string, offset, length int64) (io.ReadCloser, error) {
+	return s.bucket.Object(path).NewRangeReader(ctx, offset, length), nil
+}
+
+func (s *Service) DeleteObject(ctx context.Context, path string) error {
+
+	err := s.bucket.Object(path).Delete(ctx)
+	if err != nil && !b2.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Service) ListObjects(ctx context.Context, prefix string, opts bstorage.ListOptions) (*bstorage.ListPage, error) {
+
+	listOpts := []b2.ListOption{b2.ListPrefix(prefix)}
+	if opts.Delimiter != "" {
+		listOpts = append(listOpts, b2.ListDelimiter(opts.Delimiter))
+	}
+
+	page := &bstorage.ListPage{}
+	count := 0
+
+	// blazer's ObjectIterator has no way to resume at an arbitrary name, so
+	// PageToken resumption re-walks the (lexicographically ordered) listing
+	// from the start and skips everything up to and including the token.
+	iter := s.bucket.List(ctx, listOpts...)
+	for iter.Next() {
+		obj := iter.Object()
+
+		if opts.PageToken != "" && obj.Name() <= opts.PageToken {
+			continue
+		}
+
+		if opts.MaxResults > 0 && count >= opts.MaxResults {
+			page.NextPageToken = page.Objects[len(page.Objects)-1].Name
+			break
+		}
+
+		attrs, err := obj.Attrs(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		page.Objects = append(page.Objects, bstorage.ObjectInfo{
+			Name:        obj.Name(),
+			Size:        attrs.Size,
+			Updated:     attrs.UploadTimestamp,
+			ContentType: attrs.ContentType,
+			SHA1:        attrs.SHA1,
+		})
+		count++
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	return page, nil
+}
+
+func (s *Service) StatObject(ctx context.Context, path string) (*bstorage.ObjectInfo, error) {
+
+	obj := s.bucket.Object(path)
+	attrs, err := obj.Attrs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &bstorage.ObjectInfo{
+		Name:        path,
+		Size:        attrs.Size,
+		Updated:     attrs.UploadTimestamp,
+		ContentType: attrs.ContentType,
+		SHA1:        attrs.SHA1,
+	}, nil
+}
+
+// SignURL returns a B2 download authorisation URL valid until opts.Expires
+// (one hour when unset). opts.Method and opts.ContentType are not used: the
+// URL only permits downloads, also when Method is PUT.
+func (s *Service) SignURL(ctx context.Context, path string, opts bstorage.SignOptions) (string, error) {
+
+	validDuration := time.Hour
+	if !opts.Expires.IsZero() {
+		validDuration = time.Until(opts.Expires)
+	}
+
+	url, err := s.bucket.Object(path).AuthURL(ctx, validDuration, "")
+	if err != nil {
+		return "", err
+	}
+
+	return url.String(), nil
+}
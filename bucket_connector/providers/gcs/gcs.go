@@ -0,0 +1,365 @@
+// Package gcs is the Google Cloud Storage implementation of
+// bucket_connector/storage.Service.
+package gcs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/spf13/viper"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+
+	gcstorage "cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+
+	"github.com/weedbox/gcp-modules/bucket_connector/storage"
+)
+
+// DefaultJsonKey is not applied automatically; it documents the filename
+// deployments historically used when opting into file-based credentials.
+const DefaultJsonKey = "gcp.json"
+
+// gcsScope is the OAuth2 scope requested when parsing an inline JSON key
+// blob via google.JWTConfigFromJSON.
+const gcsScope = "https://www.googleapis.com/auth/devstorage.full_control"
+
+// defaultListPageSize is used whenever ListOptions.MaxResults is unset;
+// iterator.NewPager requires a positive page size, and this matches the GCS
+// JSON API's own default.
+const defaultListPageSize = 1000
+
+type Params struct {
+	fx.In
+
+	Lifecycle fx.Lifecycle
+	Logger    *zap.Logger
+
+	// HTTPClient, when supplied, is used instead of building one from a
+	// JSON key file or inline key data. Handy for tests and proxies.
+	HTTPClient *http.Client `optional:"true"`
+	// TokenSource, when supplied, takes precedence over json_key and
+	// json_key_data for authenticating the GCS client.
+	TokenSource oauth2.TokenSource `optional:"true"`
+}
+
+// Service is the GCS-backed storage.Service.
+type Service struct {
+	params Params
+	logger *zap.Logger
+	client *gcstorage.Client
+	scope  string
+
+	// signEmail and signPrivateKey are parsed once from the JSON key at
+	// startup and reused for every SignURL call.
+	signEmail      string
+	signPrivateKey []byte
+}
+
+// Module registers the GCS provider under scope and supplies a
+// storage.Service for downstream consumers.
+func Module(scope string) fx.Option {
+
+	var s *Service
+
+	return fx.Module(
+		scope,
+		fx.Provide(func(p Params) *Service {
+
+			s = &Service{
+				params: p,
+				logger: p.Logger.Named(scope),
+				scope:  scope,
+			}
+
+			s.initDefaultConfigs()
+
+			return s
+		}),
+		fx.Provide(func(svc *Service) storage.Service { return svc }),
+		fx.Populate(&s),
+		fx.Invoke(func(p Params) {
+			p.Lifecycle.Append(
+				fx.Hook{
+					OnStart: s.onStart,
+					OnStop:  s.onStop,
+				},
+			)
+		}),
+	)
+}
+
+func (s *Service) getConfigPath(key string) string {
+	return fmt.Sprintf("%s.%s", s.scope, key)
+}
+
+func (s *Service) initDefaultConfigs() {
+	viper.SetDefault(s.getConfigPath("bucket_name"), "example.com")
+
+	// json_key is left unset by default (rather than defaulting to
+	// DefaultJsonKey) so that leaving it unconfigured actually falls through
+	// to an injected TokenSource/HTTPClient or Application Default
+	// Credentials, as clientOption/loadSigningCredentials expect. Deployments
+	// that want file-based credentials must set json_key explicitly.
+}
+
+func (s *Service) onStart(ctx context.Context) error {
+
+	jsonKey := viper.GetString(s.getConfigPath("json_key"))
+	jsonKeyData := viper.GetString(s.getConfigPath("json_key_data"))
+
+	s.logger.Info("Starting GCS provider",
+		zap.String("bucket_name", viper.GetString(s.getConfigPath("bucket_name"))),
+		zap.String("json_key", jsonKey),
+	)
+
+	if err := s.loadSigningCredentials(jsonKey, jsonKeyData); err != nil {
+		s.logger.Error("loadSigningCredentials Error")
+		return err
+	}
+
+	opts, err := s.clientOptions(jsonKey, jsonKeyData)
+	if err != nil {
+		s.logger.Error("clientOptions Error")
+		return err
+	}
+
+	// storage.NewClient applies the SDK's default retry policy to every
+	// operation, including chunked writes, so transient failures during
+	// Upload are retried without extra plumbing here.
+	client, err := gcstorage.NewClient(ctx, opts...)
+	if err != nil {
+		s.logger.Error("storage.NewClient Error")
+		return err
+	}
+
+	s.client = client
+
+	return nil
+}
+
+// clientOptions picks the auth mode for the GCS client, in order of
+// precedence: an injected oauth2.TokenSource, an injected *http.Client, an
+// inline JSON key blob, a JSON key file, or (when jsonKey is empty)
+// Application Default Credentials. When endpoint is configured, it's added
+// on top so the client can be pointed at a GCS-compatible emulator instead
+// of the real API (used by bctest to record fixtures without live GCS).
+func (s *Service) clientOptions(jsonKey, jsonKeyData string) ([]option.ClientOption, error) {
+
+	var opts []option.ClientOption
+	if endpoint := viper.GetString(s.getConfigPath("endpoint")); endpoint != "" {
+		opts = append(opts, option.WithEndpoint(endpoint))
+	}
+
+	switch {
+	case s.params.TokenSource != nil:
+		return append(opts, option.WithTokenSource(s.params.TokenSource)), nil
+
+	case s.params.HTTPClient != nil:
+		return append(opts, option.WithHTTPClient(s.params.HTTPClient)), nil
+
+	case jsonKeyData != "":
+		cfg, err := google.JWTConfigFromJSON([]byte(jsonKeyData), gcsScope)
+		if err != nil {
+			return nil, err
+		}
+		return append(opts, option.WithTokenSource(cfg.TokenSource(context.Background()))), nil
+
+	case jsonKey != "":
+		return append(opts, option.WithCredentialsFile(jsonKey)), nil
+
+	default:
+		return opts, nil
+	}
+}
+
+// loadSigningCredentials parses the JSON key once into its PrivateKey/Email
+// so SignURL can sign URLs without re-reading the key file on every call.
+// It is a no-op when no JSON key is configured (e.g. ADC or an injected
+// TokenSource), in which case SignURL falls back to the GCS SDK's own
+// credential-based signing.
+func (s *Service) loadSigningCredentials(jsonKey, jsonKeyData string) error {
+
+	data := []byte(jsonKeyData)
+	if len(data) == 0 && jsonKey != "" {
+		var err error
+		data, err = os.ReadFile(jsonKey)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(data) == 0 {
+		return nil
+	}
+
+	cfg, err := google.JWTConfigFromJSON(data, gcsScope)
+	if err != nil {
+		return err
+	}
+
+	s.signEmail = cfg.Email
+	s.signPrivateKey = cfg.PrivateKey
+
+	return nil
+}
+
+func (s *Service) onStop(ctx context.Context) error {
+	s.logger.Info("Stopped GCS provider")
+	return s.client.Close()
+}
+
+func (s *Service) bucket() *gcstorage.BucketHandle {
+	bucketName := viper.GetString(s.getConfigPath("bucket_name"))
+	return s.client.Bucket(bucketName)
+}
+
+// GetClient exposes the underlying GCS client for callers that need
+// GCS-specific behaviour beyond storage.Service.
+func (s *Service) GetClient() *gcstorage.Client {
+	return s.client
+}
+
+// GetBucket exposes the underlying GCS bucket handle for callers that need
+// GCS-specific behaviour beyond storage.Service.
+func (s *Service) GetBucket() *gcstorage.BucketHandle {
+	return s.bucket()
+}
+
+func (s *Service) UploadObject(ctx context.Context, path string, r io.Reader, opts storage.UploadOptions) (*storage.ObjectInfo, error) {
+
+	w := s.bucket().Object(path).NewWriter(ctx)
+	if opts.ContentType != "" {
+		w.ContentType = opts.ContentType
+	}
+	if opts.CacheControl != "" {
+		w.CacheControl = opts.CacheControl
+	}
+	if opts.Metadata != nil {
+		w.Metadata = opts.Metadata
+	}
+	if opts.ChunkSize > 0 {
+		w.ChunkSize = opts.ChunkSize
+	}
+	if opts.PredefinedACL != "" {
+		w.PredefinedACL = opts.PredefinedACL
+	} else if !opts.Private {
+		w.ACL = []gcstorage.ACLRule{
+			{
+				Entity: gcstorage.AllUsers,
+				Role:   gcstorage.RoleReader,
+			},
+		}
+	}
+
+	if _, err := io.Copy(w, r); err != nil {
+		return nil, err
+	}
+
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return attrsToObjectInfo(w.Attrs()), nil
+}
+
+func (s *Service) DownloadObject(ctx context.Context, path string) (io.ReadCloser, error) {
+	return s.bucket().Object(path).NewReader(ctx)
+}
+
+func (s *Service) DownloadObjectRange(ctx context.Context, path string, offset, length int64) (io.ReadCloser, error) {
+	return s.bucket().Object(path).NewRangeReader(ctx, offset, length)
+}
+
+func (s *Service) DeleteObject(ctx context.Context, path string) error {
+
+	err := s.bucket().Object(path).Delete(ctx)
+	if err != nil {
+		if err == gcstorage.ErrObjectNotExist {
+			return nil
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (s *Service) ListObjects(ctx context.Context, prefix string, opts storage.ListOptions) (*storage.ListPage, error) {
+
+	it := s.bucket().Objects(ctx, &gcstorage.Query{
+		Prefix:    prefix,
+		Delimiter: opts.Delimiter,
+	})
+
+	pageSize := opts.MaxResults
+	if pageSize <= 0 {
+		pageSize = defaultListPageSize
+	}
+	pager := iterator.NewPager(it, pageSize, opts.PageToken)
+
+	var attrsPage []*gcstorage.ObjectAttrs
+	nextToken, err := pager.NextPage(&attrsPage)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &storage.ListPage{NextPageToken: nextToken}
+	for _, attrs := range attrsPage {
+		if attrs.Prefix != "" {
+			page.Prefixes = append(page.Prefixes, attrs.Prefix)
+			continue
+		}
+		page.Objects = append(page.Objects, *attrsToObjectInfo(attrs))
+	}
+
+	return page, nil
+}
+
+func (s *Service) StatObject(ctx context.Context, path string) (*storage.ObjectInfo, error) {
+
+	attrs, err := s.bucket().Object(path).Attrs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return attrsToObjectInfo(attrs), nil
+}
+
+func (s *Service) SignURL(ctx context.Context, path string, opts storage.SignOptions) (string, error) {
+
+	method := opts.Method
+	if method == "" {
+		method = "GET"
+	}
+
+	signOpts := &gcstorage.SignedURLOptions{
+		Method:      method,
+		Expires:     opts.Expires,
+		ContentType: opts.ContentType,
+	}
+
+	if s.signEmail != "" && len(s.signPrivateKey) > 0 {
+		signOpts.GoogleAccessID = s.signEmail
+		signOpts.PrivateKey = s.signPrivateKey
+	}
+
+	return s.bucket().SignedURL(path, signOpts)
+}
+
+func attrsToObjectInfo(attrs *gcstorage.ObjectAttrs) *storage.ObjectInfo {
+	return &storage.ObjectInfo{
+		Name:        attrs.Name,
+		Size:        attrs.Size,
+		Updated:     attrs.Updated,
+		ContentType: attrs.ContentType,
+		MD5:         fmt.Sprintf("%x", attrs.MD5),
+		CRC32C:      fmt.Sprintf("%08x", attrs.CRC32C),
+		Generation:  fmt.Sprintf("%d", attrs.Generation),
+	}
+}
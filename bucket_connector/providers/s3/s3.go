@@ -0,0 +1,312 @@
+// Package s3 is the AWS S3 implementation of bucket_connector/storage.Service.
+package s3
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/spf13/viper"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+
+	bstorage "github.com/weedbox/gcp-modules/bucket_connector/storage"
+)
+
+type Params struct {
+	fx.In
+
+	Lifecycle fx.Lifecycle
+	Logger    *zap.Logger
+}
+
+// Service is the S3-backed storage.Service.
+type Service struct {
+	params Params
+	logger *zap.Logger
+	client *s3.Client
+	scope  string
+}
+
+// Module registers the S3 provider under scope and supplies a
+// storage.Service for downstream consumers.
+func Module(scope string) fx.Option {
+
+	var s *Service
+
+	return fx.Module(
+		scope,
+		fx.Provide(func(p Params) *Service {
+
+			s = &Service{
+				params: p,
+				logger: p.Logger.Named(scope),
+				scope:  scope,
+			}
+
+			s.initDefaultConfigs()
+
+			return s
+		}),
+		fx.Provide(func(svc *Service) bstorage.Service { return svc }),
+		fx.Populate(&s),
+		fx.Invoke(func(p Params) {
+			p.Lifecycle.Append(
+				fx.Hook{
+					OnStart: s.onStart,
+					OnStop:  s.onStop,
+				},
+			)
+		}),
+	)
+}
+
+func (s *Service) getConfigPath(key string) string {
+	return fmt.Sprintf("%s.%s", s.scope, key)
+}
+
+func (s *Service) initDefaultConfigs() {
+	viper.SetDefault(s.getConfigPath("bucket_name"), "example.com")
+	viper.SetDefault(s.getConfigPath("region"), "us-east-1")
+}
+
+func (s *Service) onStart(ctx context.Context) error {
+
+	bucketName := viper.GetString(s.getConfigPath("bucket_name"))
+	region := viper.GetString(s.getConfigPath("region"))
+
+	s.logger.Info("Starting S3 provider",
+		zap.String("bucket_name", bucketName),
+		zap.String("region", region),
+	)
+
+	var optFns []func(*config.LoadOptions) error
+	optFns = append(optFns, config.WithRegion(region))
+
+	if accessKey := viper.GetString(s.getConfigPath("access_key_id")); accessKey != "" {
+		secretKey := viper.GetString(s.getConfigPath("secret_access_key"))
+		optFns = append(optFns, config.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(accessKey, secretKey, ""),
+		))
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, optFns...)
+	if err != nil {
+		s.logger.Error("config.LoadDefaultConfig Error")
+		return err
+	}
+
+	s.client = s3.NewFromConfig(cfg, func(o *s3.Options) {
+		if endpoint := viper.GetString(s.getConfigPath("endpoint")); endpoint != "" {
+			o.BaseEndpoint = aws.String(endpoint)
+		}
+	})
+
+	return nil
+}
+
+func (s *Service) onStop(ctx context.Context) error {
+	s.logger.Info("Stopped S3 provider")
+	return nil
+}
+
+func (s *Service) bucketName() string {
+	return viper.GetString(s.getConfigPath("bucket_name"))
+}
+
+func (s *Service) UploadObject(ctx context.Context, path string, r io.Reader, opts bstorage.UploadOptions) (*bstorage.ObjectInfo, error) {
+
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(s.bucketName()),
+		Key:    aws.String(path),
+		Body:   r,
+	}
+	if opts.ContentType != "" {
+		input.ContentType = aws.String(opts.ContentType)
+	}
+	if opts.CacheControl != "" {
+		input.CacheControl = aws.String(opts.CacheControl)
+	}
+	if opts.Metadata != nil {
+		input.Metadata = opts.Metadata
+	}
+	if opts.PredefinedACL != "" {
+		input.ACL = s3types.ObjectCannedACL(opts.PredefinedACL)
+	} else if !opts.Private {
+		input.ACL = s3types.ObjectCannedACLPublicRead
+	}
+
+	uploader := manager.NewUploader(s.client, func(u *manager.Uploader) {
+		if opts.ChunkSize > 0 {
+			u.PartSize = int64(opts.ChunkSize)
+		}
+	})
+	if _, err := uploader.Upload(ctx, input); err != nil {
+		return nil, err
+	}
+
+	return s.StatObject(ctx, path)
+}
+
+func (s *Service) DownloadObject(ctx context.Context, path string) (io.ReadCloser, error) {
+
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucketName()),
+		Key:    aws.String(path),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return out.Body, nil
+}
+
+func (s *Service) DownloadObjectRange(ctx context.Context, path string, offset, length int64) (io.ReadCloser, error) {
+
+	rangeHeader := fmt.Sprintf("bytes=%d-", offset)
+	if length >= 0 {
+		rangeHeader = fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)
+	}
+
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucketName()),
+		Key:    aws.String(path),
+		Range:  aws.String(rangeHeader),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return out.Body, nil
+}
+
+func (s *Service) DeleteObject(ctx context.Context, path string) error {
+
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucketName()),
+		Key:    aws.String(path),
+	})
+
+	return err
+}
+
+func (s *Service) ListObjects(ctx context.Context, prefix string, opts bstorage.ListOptions) (*bstorage.ListPage, error) {
+
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucketName()),
+		Prefix: aws.String(prefix),
+	}
+	if opts.Delimiter != "" {
+		input.Delimiter = aws.String(opts.Delimiter)
+	}
+	if opts.MaxResults > 0 {
+		input.MaxKeys = aws.Int32(int32(opts.MaxResults))
+	}
+	if opts.PageToken != "" {
+		input.ContinuationToken = aws.String(opts.PageToken)
+	}
+
+	out, err := s.client.ListObjectsV2(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &bstorage.ListPage{}
+	if aws.ToBool(out.IsTruncated) {
+		page.NextPageToken = aws.ToString(out.NextContinuationToken)
+	}
+
+	for _, obj := range out.Contents {
+		page.Objects = append(page.Objects, bstorage.ObjectInfo{
+			Name:    aws.ToString(obj.Key),
+			Size:    aws.ToInt64(obj.Size),
+			Updated: aws.ToTime(obj.LastModified),
+			MD5:     etagToMD5(obj.ETag),
+		})
+	}
+
+	for _, p := range out.CommonPrefixes {
+		page.Prefixes = append(page.Prefixes, aws.ToString(p.Prefix))
+	}
+
+	return page, nil
+}
+
+func (s *Service) StatObject(ctx context.Context, path string) (*bstorage.ObjectInfo, error) {
+
+	out, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucketName()),
+		Key:    aws.String(path),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &bstorage.ObjectInfo{
+		Name:        path,
+		Size:        aws.ToInt64(out.ContentLength),
+		Updated:     aws.ToTime(out.LastModified),
+		ContentType: aws.ToString(out.ContentType),
+		MD5:         etagToMD5(out.ETag),
+	}, nil
+}
+
+// etagToMD5 returns the object's MD5 digest from an S3 ETag, or "" when the
+// ETag isn't one: multipart uploads (the default once manager.Uploader
+// splits a body across parts) report an ETag of the form "<hex>-<partCount>",
+// which is not an MD5 of the object and must not be compared against one.
+func etagToMD5(etag *string) string {
+
+	v := strings.Trim(aws.ToString(etag), `"`)
+	if strings.Contains(v, "-") {
+		return ""
+	}
+
+	return v
+}
+
+func (s *Service) SignURL(ctx context.Context, path string, opts bstorage.SignOptions) (string, error) {
+
+	presignClient := s3.NewPresignClient(s.client)
+
+	expires := func(o *s3.PresignOptions) {
+		if !opts.Expires.IsZero() {
+			o.Expires = time.Until(opts.Expires)
+		}
+	}
+
+	method := opts.Method
+	if method == "" {
+		method = "GET"
+	}
+
+	switch method {
+	case "PUT":
+		req, err := presignClient.PresignPutObject(ctx, &s3.PutObjectInput{
+			Bucket:      aws.String(s.bucketName()),
+			Key:         aws.String(path),
+			ContentType: aws.String(opts.ContentType),
+		}, expires)
+		if err != nil {
+			return "", err
+		}
+		return req.URL, nil
+	default:
+		req, err := presignClient.PresignGetObject(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(s.bucketName()),
+			Key:    aws.String(path),
+		}, expires)
+		if err != nil {
+			return "", err
+		}
+		return req.URL, nil
+	}
+}
@@ -0,0 +1,118 @@
+// Package storage defines the storage-vendor-agnostic surface that
+// bucket_connector depends on. Each supported backend (GCS, S3, Azure Blob,
+// B2) lives in its own provider package under bucket_connector/providers and
+// implements Service; bucket_connector selects between them at startup via
+// the `<scope>.provider` config key.
+package storage
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// Provider identifies which backend implements Service for a scope.
+type Provider string
+
+const (
+	ProviderGCS   Provider = "gcs"
+	ProviderS3    Provider = "s3"
+	ProviderAzure Provider = "azure"
+	ProviderB2    Provider = "b2"
+
+	DefaultProvider = ProviderGCS
+)
+
+// ObjectInfo is a lean, backend-agnostic description of a stored object.
+type ObjectInfo struct {
+	Name        string
+	Size        int64
+	Updated     time.Time
+	ContentType string
+	// MD5 is the hex-encoded MD5 digest of the object, when the backend
+	// exposes one. Empty when not supported, or when the backend's digest
+	// (e.g. an S3 multipart ETag) isn't actually an MD5 of the object.
+	MD5 string
+	// CRC32C is the hex-encoded CRC32C checksum of the object, when the
+	// backend exposes one (e.g. GCS). Empty when not supported.
+	CRC32C string
+	// SHA1 is the hex-encoded SHA1 digest of the object, when the backend
+	// exposes one (e.g. B2). Empty when not supported.
+	SHA1 string
+	// Generation is the backend's version/generation marker for the object,
+	// when it has one (e.g. GCS object generation). Empty when not supported.
+	Generation string
+}
+
+// UploadOptions controls how an object is written by UploadObject.
+type UploadOptions struct {
+	ContentType string
+	// Private marks the object so it is not made world-readable; it is only
+	// reachable through a signed URL from SignURL.
+	Private bool
+	// ChunkSize is the size, in bytes, of the chunks the upload is split
+	// into. Zero uses the backend's default.
+	ChunkSize    int
+	CacheControl string
+	Metadata     map[string]string
+	// PredefinedACL names a backend-specific canned ACL (e.g. GCS's
+	// "publicRead") to apply instead of the default ACLRule-based one.
+	PredefinedACL string
+	// Progress, when set, is invoked by BucketConnector.Upload each time the
+	// provider reads from the source, with the cumulative number of bytes
+	// read so far. It is not tied to chunk flushes: the count includes bytes
+	// still buffered and not yet acknowledged by the backend.
+	Progress func(bytesSent int64)
+}
+
+// ListOptions controls pagination and pseudo-directory grouping for
+// ListObjects/WalkObjects.
+type ListOptions struct {
+	// Delimiter groups object names sharing a prefix up to the delimiter
+	// into Prefixes instead of listing every object beneath them, letting
+	// callers browse GCS/S3-style buckets as if they had directories.
+	Delimiter string
+	// MaxResults caps the number of objects returned per page. Zero uses
+	// the backend's default page size.
+	MaxResults int
+	// PageToken resumes a listing from where a previous ListPage left off;
+	// it must come from that page's NextPageToken.
+	PageToken string
+}
+
+// ListPage is one page of a ListObjects/WalkObjects call.
+type ListPage struct {
+	Objects []ObjectInfo
+	// Prefixes holds the pseudo-directories discovered when ListOptions.
+	// Delimiter is set.
+	Prefixes []string
+	// NextPageToken is non-empty when more pages are available.
+	NextPageToken string
+}
+
+// SignOptions controls signed URL generation via SignURL.
+type SignOptions struct {
+	// Method is the HTTP method the signed URL is valid for, e.g. GET or PUT.
+	Method string
+	// Expires is when the signed URL stops being valid.
+	Expires time.Time
+	// ContentType must match the Content-Type header of the signed request
+	// when Method is PUT.
+	ContentType string
+}
+
+// Service is the storage-vendor-agnostic interface implemented by every
+// provider package.
+type Service interface {
+	UploadObject(ctx context.Context, path string, r io.Reader, opts UploadOptions) (*ObjectInfo, error)
+	DownloadObject(ctx context.Context, path string) (io.ReadCloser, error)
+	// DownloadObjectRange returns the object contents starting at offset and
+	// spanning length bytes. A length < 0 reads to the end of the object.
+	DownloadObjectRange(ctx context.Context, path string, offset, length int64) (io.ReadCloser, error)
+	// DeleteObject removes path. It is idempotent: deleting a path that
+	// doesn't exist returns nil, not an error.
+	DeleteObject(ctx context.Context, path string) error
+	ListObjects(ctx context.Context, prefix string, opts ListOptions) (*ListPage, error)
+	StatObject(ctx context.Context, path string) (*ObjectInfo, error)
+	SignURL(ctx context.Context, path string, opts SignOptions) (string, error)
+}
@@ -0,0 +1,98 @@
+package bucket_connector
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+
+	"github.com/weedbox/gcp-modules/bucket_connector/storage"
+)
+
+var crc32cTable = crc32.MakeTable(crc32.Castagnoli)
+
+// Upload streams r to path, reporting progress through opts.Progress and
+// verifying the object against a client-side MD5/CRC32C/SHA1 checksum once
+// the backend reports one back. Progress reporting and checksum
+// verification are implemented once here so every storage.Service provider
+// gets them for free; opts.ChunkSize is passed through to the provider,
+// which does the chunking.
+func (c *BucketConnector) Upload(ctx context.Context, path string, r io.Reader, opts storage.UploadOptions) (*storage.ObjectInfo, error) {
+
+	cr := &checksummingReader{
+		r:        r,
+		md5:      md5.New(),
+		crc32c:   crc32.New(crc32cTable),
+		sha1:     sha1.New(),
+		progress: opts.Progress,
+	}
+
+	info, err := c.storage.UploadObject(ctx, path, cr, opts)
+	if err != nil {
+		c.logger.Error("UploadObject Error")
+		return nil, err
+	}
+
+	if err := cr.verify(info); err != nil {
+		c.logger.Error("Upload checksum mismatch")
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// checksummingReader tees everything read through it into MD5/CRC32C/SHA1
+// hashes and reports cumulative bytes read via progress, if set.
+type checksummingReader struct {
+	r        io.Reader
+	md5      hash.Hash
+	crc32c   hash.Hash32
+	sha1     hash.Hash
+	progress func(bytesSent int64)
+	sent     int64
+}
+
+func (cr *checksummingReader) Read(p []byte) (int, error) {
+
+	n, err := cr.r.Read(p)
+	if n > 0 {
+		cr.md5.Write(p[:n])
+		cr.crc32c.Write(p[:n])
+		cr.sha1.Write(p[:n])
+		cr.sent += int64(n)
+		if cr.progress != nil {
+			cr.progress(cr.sent)
+		}
+	}
+
+	return n, err
+}
+
+// verify compares the client-computed checksums against whatever the
+// backend reported, skipping checks the backend doesn't expose a value for.
+func (cr *checksummingReader) verify(info *storage.ObjectInfo) error {
+
+	if info.MD5 != "" {
+		if got := hex.EncodeToString(cr.md5.Sum(nil)); got != info.MD5 {
+			return fmt.Errorf("upload checksum mismatch: MD5 got %s, backend reports %s", got, info.MD5)
+		}
+	}
+
+	if info.CRC32C != "" {
+		if got := fmt.Sprintf("%08x", cr.crc32c.Sum32()); got != info.CRC32C {
+			return fmt.Errorf("upload checksum mismatch: CRC32C got %s, backend reports %s", got, info.CRC32C)
+		}
+	}
+
+	if info.SHA1 != "" {
+		if got := hex.EncodeToString(cr.sha1.Sum(nil)); got != info.SHA1 {
+			return fmt.Errorf("upload checksum mismatch: SHA1 got %s, backend reports %s", got, info.SHA1)
+		}
+	}
+
+	return nil
+}